@@ -0,0 +1,92 @@
+// Copyright 2017 ZhongAn Information Technology Services Co.,Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	pbtypes "github.com/dappledger/AnnChain/angine/protos/types"
+	"github.com/dappledger/AnnChain/module/xlib/def"
+)
+
+// CommitCache wraps a block's precommit votes for a given (height, round),
+// cached in whatever shape ValidatorSet.VerifyCommit/VoterSet.VerifyCommit
+// need to check +2/3 voting power without re-deriving it from raw votes.
+//
+// Precommits is indexed by validator/voter index, matching
+// ValidatorSet.GetByIndex and VoterSet.GetByIndex; a nil slot means that
+// signer was not heard from in this round.
+//
+// AggregatedSignature and ParticipationBitmap are the BLS aggregate-commit
+// fast path: when both are non-empty, VerifyCommit checks the aggregate
+// signature against the participating signers instead of walking
+// Precommits one at a time. Chains that never produce them see the zero
+// value and VerifyCommit falls back to the per-signature loop.
+type CommitCache struct {
+	Precommits []*Precommit
+
+	AggregatedSignature []byte
+	ParticipationBitmap []byte
+
+	height def.INT
+	round  int
+}
+
+// NewCommitCache builds a CommitCache for a plain per-signature commit.
+func NewCommitCache(height def.INT, round int, precommits []*Precommit) *CommitCache {
+	return &CommitCache{
+		Precommits: precommits,
+		height:     height,
+		round:      round,
+	}
+}
+
+// NewAggregatedCommitCache builds a CommitCache carrying an aggregated
+// signature and participation bitmap instead of individual Precommits.
+func NewAggregatedCommitCache(height def.INT, round int, aggregatedSignature, participationBitmap []byte) *CommitCache {
+	return &CommitCache{
+		height:              height,
+		round:               round,
+		AggregatedSignature: aggregatedSignature,
+		ParticipationBitmap: participationBitmap,
+	}
+}
+
+func (cc *CommitCache) Height() def.INT {
+	return cc.height
+}
+
+func (cc *CommitCache) Round() int {
+	return cc.round
+}
+
+// Precommit is a single signer's precommit vote within a CommitCache.
+type Precommit struct {
+	Signature []byte
+
+	data *pbtypes.Vote
+}
+
+func NewPrecommit(data *pbtypes.Vote, signature []byte) *Precommit {
+	return &Precommit{data: data, Signature: signature}
+}
+
+// Exist reports whether this signer actually precommitted this round; a
+// nil *Precommit means they were skipped.
+func (p *Precommit) Exist() bool {
+	return p != nil && p.data != nil
+}
+
+func (p *Precommit) GetData() *pbtypes.Vote {
+	return p.data
+}
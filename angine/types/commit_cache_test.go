@@ -0,0 +1,50 @@
+// Copyright 2017 ZhongAn Information Technology Services Co.,Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+
+	pbtypes "github.com/dappledger/AnnChain/angine/protos/types"
+)
+
+// fullParticipationBitmap returns a bitmap with every one of the first n
+// bits set, i.e. every validator is marked as a contributor.
+func fullParticipationBitmap(n int) []byte {
+	bitmap := make([]byte, (n+7)/8)
+	for idx := 0; idx < n; idx++ {
+		bitmap[idx/8] |= 1 << uint(idx%8)
+	}
+	return bitmap
+}
+
+// TestVerifyCommitAggregateFallsBackWithoutBLS checks that, on a build
+// without the bls tag, VerifyCommit's aggregate fast path reports
+// ErrUnsupportedAggregate instead of silently treating the commit as valid
+// or invalid -- the default PubKey implementation here never satisfies
+// AggregatablePubKey, so there is no safe way to check the signature.
+func TestVerifyCommitAggregateFallsBackWithoutBLS(t *testing.T) {
+	valSet, _, _ := RandValidatorSet(zap.NewNop(), 4, 1)
+	blockID := pbtypes.BlockID{Hash: []byte("a block hash")}
+
+	commit := NewAggregatedCommitCache(1, 0, []byte("not a real aggregate signature"), fullParticipationBitmap(valSet.Size()))
+
+	err := valSet.VerifyCommit("test-chain", blockID, 1, commit)
+	if err != ErrUnsupportedAggregate {
+		t.Fatalf("VerifyCommit: got err %v, want ErrUnsupportedAggregate", err)
+	}
+}
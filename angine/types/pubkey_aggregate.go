@@ -0,0 +1,38 @@
+// Copyright 2017 ZhongAn Information Technology Services Co.,Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "errors"
+
+// ErrUnsupportedAggregate is returned when ValidatorSet.VerifyCommit is
+// asked to check an aggregated signature but the commit's validators carry
+// a PubKey type that doesn't implement AggregatablePubKey (i.e. the chain
+// wasn't built with `-tags bls`).
+var ErrUnsupportedAggregate = errors.New("pubkey: signature aggregation unsupported for this key type")
+
+// AggregatablePubKey is implemented by PubKey types that support combining
+// several public keys into one, so a single signature verification can
+// stand in for one-per-signer. The only implementation in this repo is the
+// BLS12-381 key behind the `bls` build tag (see pubkey_bls.go); plain keys
+// never satisfy this interface, which is exactly how VerifyCommit tells
+// whether the aggregate fast path is available.
+type AggregatablePubKey interface {
+	PubKey
+	// Aggregate combines pk with every key in others into the single
+	// public key that verifies an aggregate signature over the same
+	// message signed individually by each contributing key. It returns
+	// nil if any key in others isn't the same concrete type as pk.
+	Aggregate(others []PubKey) PubKey
+}
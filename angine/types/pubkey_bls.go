@@ -0,0 +1,104 @@
+// Copyright 2017 ZhongAn Information Technology Services Co.,Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build bls
+
+package types
+
+import (
+	gobls "github.com/dappledger/AnnChain/module/lib/go-crypto/bls"
+	"go.uber.org/zap"
+
+	"github.com/dappledger/AnnChain/module/xlib/def"
+)
+
+// BLSPubKey is a BLS12-381 public key. It's only linked in by builds that
+// pass `-tags bls`; every other build has no AggregatablePubKey
+// implementation at all, so ValidatorSet.VerifyCommit's aggregate fast
+// path always falls back to ErrUnsupportedAggregate there.
+type BLSPubKey struct {
+	Point gobls.G1
+}
+
+func (pk *BLSPubKey) Address() []byte {
+	return gobls.Hash160(pk.Point.Marshal())
+}
+
+func (pk *BLSPubKey) Bytes() []byte {
+	return pk.Point.Marshal()
+}
+
+func (pk *BLSPubKey) VerifyBytes(msg []byte, sig Signature) bool {
+	blsSig, ok := sig.(*BLSSignature)
+	if !ok {
+		return false
+	}
+	return gobls.Verify(&pk.Point, msg, &blsSig.Point)
+}
+
+// Aggregate sums pk's curve point with every key in others, producing the
+// public key that verifies a BLS aggregate signature over the same message
+// signed individually by each contributing key.
+func (pk *BLSPubKey) Aggregate(others []PubKey) PubKey {
+	agg := pk.Point
+	for _, other := range others {
+		o, ok := other.(*BLSPubKey)
+		if !ok {
+			return nil
+		}
+		agg = gobls.AddG1(&agg, &o.Point)
+	}
+	return &BLSPubKey{Point: agg}
+}
+
+// BLSSignature wraps a BLS12-381 G2 point, the aggregate-friendly signature
+// counterpart to BLSPubKey.
+type BLSSignature struct {
+	Point gobls.G2
+}
+
+func (sig *BLSSignature) Bytes() []byte {
+	return sig.Point.Marshal()
+}
+
+// RandBLSValidatorSet behaves like RandValidatorSet but swaps each
+// validator's PubKey/PrivKey for a freshly generated BLS12-381 key pair, so
+// tests can exercise VerifyCommit's aggregated-signature fast path. Only
+// built with `-tags bls`.
+func RandBLSValidatorSet(logger *zap.Logger, numValidators int, votingPower def.INT) (*ValidatorSet, *VoterSet, []*PrivValidator) {
+	valSet, _, privVals := RandValidatorSet(logger, numValidators, votingPower)
+	for i, val := range valSet.Validators {
+		priv := gobls.GenPrivKey()
+		pub := &BLSPubKey{Point: *priv.PubKeyPoint()}
+		val.PubKey = pub
+		privVals[i].PrivKey = &blsPrivKey{priv}
+	}
+	// Re-sample after the key swap so the voters carry the BLS keys too.
+	voterSet := valSet.SelectVoters(valSet.Hash(), numValidators)
+	return valSet, voterSet, privVals
+}
+
+// blsPrivKey adapts a raw gobls private scalar to this package's PrivKey
+// interface so it can sit in a PrivValidator alongside the other key types.
+type blsPrivKey struct {
+	scalar gobls.PrivKey
+}
+
+func (pk *blsPrivKey) Sign(msg []byte) Signature {
+	return &BLSSignature{Point: gobls.Sign(pk.scalar, msg)}
+}
+
+func (pk *blsPrivKey) PubKey() PubKey {
+	return &BLSPubKey{Point: *pk.scalar.PubKeyPoint()}
+}
@@ -18,6 +18,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
 	"sort"
 	"strings"
 
@@ -38,11 +40,22 @@ import (
 // upon calling .IncrementAccum().
 // NOTE: Not goroutine-safe.
 // NOTE: All get/set to validators should copy the value for safety.
-// TODO: consider validator Accum overflow
-// TODO: move valset into an iavl tree where key is 'blockbonded|pubkey'
+// Accum arithmetic is overflow-guarded: see rescaleAccum and saturateAccum.
+// See ValidatorStore for the IAVL-backed alternative to this in-memory
+// slice, for chains with validator sets too large to scan linearly.
 type ValidatorSet struct {
 	Validators []*Validator // NOTE: persisted via reflect, must be exported.
 
+	// AnchorHeight is the height at which Validators' Accum values are
+	// known-good, i.e. the last point IncrementAccum was actually run
+	// against this set. GetProposerAt rolls forward from here instead of
+	// replaying IncrementAccum since genesis.
+	AnchorHeight def.INT
+	// LastProposer is the proposer elected for AnchorHeight, persisted so
+	// restarts and light clients can reconstruct the schedule without
+	// replaying every IncrementAccum call since genesis.
+	LastProposer *Validator // NOTE: persisted via reflect, must be exported.
+
 	// cached (unexported)
 	proposer         *Validator
 	totalVotingPower def.INT
@@ -77,12 +90,88 @@ func ValSetFromJsonBytes(data []byte) *ValidatorSet {
 	return genValidatorSet(vset)
 }
 
-// TODO: mind the overflow when times and votingPower shares too large.
+// maxSafeAccum bounds how far any validator's Accum is allowed to grow
+// before rescaleAccum proportionally shrinks every validator's Accum back
+// down. Keeping well under the int64 edge leaves headroom for the next
+// IncrementAccum's VotingPower*times multiply.
+const maxSafeAccum = def.INT(math.MaxInt64 / 2)
+
+var (
+	maxInt64Big = big.NewInt(math.MaxInt64)
+	minInt64Big = big.NewInt(math.MinInt64)
+)
+
+// saturateAccum clamps a math/big accumulator result back into the int64
+// range Accum is stored in, instead of letting it silently wrap.
+func saturateAccum(accum *big.Int) def.INT {
+	if accum.Cmp(maxInt64Big) > 0 {
+		return math.MaxInt64
+	}
+	if accum.Cmp(minInt64Big) < 0 {
+		return math.MinInt64
+	}
+	return def.INT(accum.Int64())
+}
+
+// rescaleAccum halves every validator's Accum once the largest one has
+// grown past maxSafeAccum. Scaling every validator by the same factor
+// preserves their relative order and the fairness invariant between them --
+// it just changes the unit Accum is counted in -- while guaranteeing the
+// next IncrementAccum's multiply has room to work in.
+func (valSet *ValidatorSet) rescaleAccum() {
+	var maxAccum def.INT
+	for _, val := range valSet.Validators {
+		abs := val.Accum
+		if abs < 0 {
+			abs = -abs
+		}
+		if abs > maxAccum {
+			maxAccum = abs
+		}
+	}
+	if maxAccum <= maxSafeAccum {
+		return
+	}
+	for _, val := range valSet.Validators {
+		val.Accum /= 2
+	}
+}
+
+// isBalanced reports whether every validator's Accum is within one
+// TotalVotingPower of every other's. IncrementAccumTo takes its apportioned
+// fast path only when this holds and otherwise runs IncrementAccum round by
+// round. The spread can exceed TotalVotingPower after a run of
+// IncrementAccum(1) calls.
+func (valSet *ValidatorSet) isBalanced() bool {
+	if len(valSet.Validators) == 0 {
+		return true
+	}
+	min, max := valSet.Validators[0].Accum, valSet.Validators[0].Accum
+	for _, val := range valSet.Validators[1:] {
+		if val.Accum < min {
+			min = val.Accum
+		}
+		if val.Accum > max {
+			max = val.Accum
+		}
+	}
+	return max-min <= valSet.TotalVotingPower()
+}
+
 func (valSet *ValidatorSet) IncrementAccum(times def.INT) {
-	// Add VotingPower * times to each validator and order into heap.
+	valSet.rescaleAccum()
+
+	// Add VotingPower * times to each validator and order into heap. The
+	// multiply/add runs in math/big so a validator with a large
+	// VotingPower times a large `times` can't wrap int64 and scramble the
+	// order the heap sees -- only the final, saturated value is written
+	// back into the int64 Accum field.
 	validatorsHeap := NewHeap()
+	timesBig := big.NewInt(int64(times))
 	for _, val := range valSet.Validators {
-		val.Accum += val.VotingPower * times // TODO: mind overflow
+		gain := new(big.Int).Mul(big.NewInt(int64(val.VotingPower)), timesBig)
+		accum := new(big.Int).Add(big.NewInt(int64(val.Accum)), gain)
+		val.Accum = saturateAccum(accum)
 		validatorsHeap.Push(val, accumComparable(val.Accum))
 	}
 
@@ -92,10 +181,92 @@ func (valSet *ValidatorSet) IncrementAccum(times def.INT) {
 		mostest := validatorsHeap.Peek().(*Validator)
 		if i == deTime {
 			valSet.proposer = mostest
+			valSet.LastProposer = mostest.Copy()
 		}
 		mostest.Accum -= valSet.TotalVotingPower()
 		validatorsHeap.Update(mostest, accumComparable(mostest.Accum))
 	}
+	valSet.AnchorHeight += times
+}
+
+// IncrementAccumTo jumps the schedule straight to targetHeight in
+// O(N log N) -- apportioning the elapsed rounds across validators by
+// voting-power share instead of looping round-by-round like
+// IncrementAccum(times) does -- so catching up a light client that skipped
+// thousands of rounds doesn't cost thousands of heap updates. It uses the
+// largest-remainder method (the same one used to apportion parliamentary
+// seats by vote share): each validator first gets floor(delta*power/total)
+// proposal slots, then the slots left over from the flooring (at most
+// len(Validators)-1) go to whichever validators had the largest fractional
+// share.
+func (valSet *ValidatorSet) IncrementAccumTo(targetHeight def.INT) {
+	delta := targetHeight - valSet.AnchorHeight
+	if delta <= 0 {
+		return
+	}
+
+	// The apportionment below only assigns each validator
+	// floor(delta*power/total) or that plus one proposal slot, which
+	// matches IncrementAccum(delta)'s round-by-round result only when no
+	// validator is already carrying a multi-round Accum lead over the
+	// others -- true right after genesis, and true as long as only
+	// IncrementAccumTo has run since. Outside that regime a validator's
+	// existing lead can win it a round the apportionment assigned to
+	// someone else, so fall back to the exact (if slower) round-by-round
+	// path instead of risking a proposer schedule that diverges from the
+	// rest of the network.
+	if !valSet.isBalanced() {
+		valSet.IncrementAccum(delta)
+		return
+	}
+	valSet.rescaleAccum()
+
+	total := big.NewInt(int64(valSet.TotalVotingPower()))
+	deltaBig := big.NewInt(int64(delta))
+
+	type apportionment struct {
+		val       *Validator
+		proposals int64
+		remainder *big.Int
+	}
+	apportionments := make([]*apportionment, len(valSet.Validators))
+	var assigned int64
+	for i, val := range valSet.Validators {
+		share := new(big.Int).Mul(big.NewInt(int64(val.VotingPower)), deltaBig)
+		proposals := new(big.Int).Quo(share, total)
+		remainder := new(big.Int).Mod(share, total)
+
+		gain := new(big.Int).Add(big.NewInt(int64(val.Accum)), share)
+		val.Accum = saturateAccum(gain)
+
+		apportionments[i] = &apportionment{val: val, proposals: proposals.Int64(), remainder: remainder}
+		assigned += proposals.Int64()
+	}
+
+	sort.SliceStable(apportionments, func(i, j int) bool {
+		return apportionments[i].remainder.Cmp(apportionments[j].remainder) > 0
+	})
+	for leftover := delta - def.INT(assigned); leftover > 0; leftover-- {
+		apportionments[int(leftover-1)%len(apportionments)].proposals++
+	}
+
+	for _, a := range apportionments {
+		a.val.Accum = saturateAccum(new(big.Int).Sub(big.NewInt(int64(a.val.Accum)), new(big.Int).Mul(big.NewInt(a.proposals), total)))
+	}
+
+	// The round-by-round loop always takes the current maximum, so the
+	// values it picks never increase: its last pick is the proposer whose
+	// final Accum is lowest (ties go to the larger VotingPower).
+	var last *apportionment
+	for _, a := range apportionments {
+		if a.proposals > 0 && (last == nil || a.val.Accum < last.val.Accum ||
+			(a.val.Accum == last.val.Accum && a.val.VotingPower > last.val.VotingPower)) {
+			last = a
+		}
+	}
+	valSet.proposer = last.val
+	valSet.LastProposer = last.val.Copy()
+	valSet.AnchorHeight = targetHeight
 }
 
 func (valSet *ValidatorSet) JSONBytes() ([]byte, error) {
@@ -108,8 +279,14 @@ func (valSet *ValidatorSet) Copy() *ValidatorSet {
 		// NOTE: must copy, since IncrementAccum updates in place.
 		validators[i] = val.Copy()
 	}
+	var lastProposer *Validator
+	if valSet.LastProposer != nil {
+		lastProposer = valSet.LastProposer.Copy()
+	}
 	return &ValidatorSet{
 		Validators:       validators,
+		AnchorHeight:     valSet.AnchorHeight,
+		LastProposer:     lastProposer,
 		proposer:         valSet.proposer,
 		totalVotingPower: valSet.totalVotingPower,
 	}
@@ -163,6 +340,37 @@ func (valSet *ValidatorSet) Proposer() (proposer *Validator) {
 	return valSet.proposer.Copy()
 }
 
+// GetProposerAt is a pure alternative to Proposer(): it recomputes the
+// proposer for (height, round) straight from the persisted Validators and
+// AnchorHeight, without mutating valSet's Accum or proposer cache. Unlike
+// Proposer(), it can't go stale if a caller forgets to call IncrementAccum,
+// or be corrupted by an Add/Update/Remove that happens mid-height -- it
+// always starts its replay from the last known-good AnchorHeight. Callers
+// should prefer this over Proposer()/the cached proposer whenever height
+// and round are known, e.g. in VerifyCommit and the consensus reactor.
+func (valSet *ValidatorSet) GetProposerAt(height def.INT, round int) *Validator {
+	if len(valSet.Validators) == 0 {
+		return nil
+	}
+	delta := (height - valSet.AnchorHeight) + def.INT(round)
+	if delta <= 0 {
+		if valSet.LastProposer != nil {
+			return valSet.LastProposer.Copy()
+		}
+		delta = 1
+	}
+
+	clone := valSet.Copy()
+	clone.proposer = nil
+	// IncrementAccumTo does the same O(N log N) apportioned jump
+	// IncrementAccum(delta)'s O(delta) loop would do round-by-round, and
+	// falls back to the exact loop itself whenever the jump isn't safe to
+	// apportion -- exactly the "light client skipped thousands of rounds"
+	// case this method exists for.
+	clone.IncrementAccumTo(valSet.AnchorHeight + delta)
+	return clone.Proposer()
+}
+
 func (valSet *ValidatorSet) Hash() []byte {
 	if len(valSet.Validators) == 0 {
 		return nil
@@ -244,13 +452,23 @@ func (valSet *ValidatorSet) Iterate(fn func(index int, val *Validator) bool) {
 
 // Verify that +2/3 of the set had signed the given signBytes
 func (valSet *ValidatorSet) VerifyCommit(chainID string, blockID pbtypes.BlockID, height def.INT, commit *CommitCache) error {
-	if valSet.Size() != len(commit.Precommits) {
-		return fmt.Errorf("Invalid commit -- wrong set size: %v vs %v", valSet.Size(), len(commit.Precommits))
-	}
 	if height != commit.Height() {
 		return fmt.Errorf("Invalid commit -- wrong height: %v vs %v", height, commit.Height())
 	}
 
+	// Fast path: an aggregated (e.g. BLS) signature plus a participation
+	// bitmap lets us do a single aggregate-key check instead of one
+	// VerifyBytes per validator. Chains that don't produce these fields
+	// (the zero value) fall straight through to the per-signature loop
+	// below, so old commits keep verifying exactly as before.
+	if len(commit.AggregatedSignature) > 0 && len(commit.ParticipationBitmap) > 0 {
+		return valSet.verifyAggregatedCommit(chainID, blockID, height, commit)
+	}
+
+	if valSet.Size() != len(commit.Precommits) {
+		return fmt.Errorf("Invalid commit -- wrong set size: %v vs %v", valSet.Size(), len(commit.Precommits))
+	}
+
 	var talliedVotingPower def.INT
 	round := commit.Round()
 
@@ -290,6 +508,63 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, blockID pbtypes.BlockID
 	}
 }
 
+// verifyAggregatedCommit checks commit.AggregatedSignature against the
+// single public key formed by aggregating every validator whose bit is set
+// in commit.ParticipationBitmap, one pairing check standing in for what
+// would otherwise be N calls to VerifyBytes. It requires every contributing
+// validator's PubKey to implement AggregatablePubKey (the BLS build tag);
+// anything else reports ErrUnsupportedAggregate so the caller knows to fall
+// back to the per-signature path instead of misreporting a bad commit.
+func (valSet *ValidatorSet) verifyAggregatedCommit(chainID string, blockID pbtypes.BlockID, height def.INT, commit *CommitCache) error {
+	var talliedVotingPower def.INT
+	var signers []PubKey
+	for idx, val := range valSet.Validators {
+		if !bitmapIsSet(commit.ParticipationBitmap, idx) {
+			continue
+		}
+		signers = append(signers, val.PubKey)
+		talliedVotingPower += val.VotingPower
+	}
+	if len(signers) == 0 {
+		return fmt.Errorf("Invalid commit -- empty participation bitmap")
+	}
+	if talliedVotingPower <= valSet.TotalVotingPower()*2/3 {
+		return fmt.Errorf("Invalid commit -- insufficient voting power: got %v, needed %v",
+			talliedVotingPower, (valSet.TotalVotingPower()*2/3 + 1))
+	}
+
+	aggregatable, ok := signers[0].(AggregatablePubKey)
+	if !ok {
+		return ErrUnsupportedAggregate
+	}
+	aggKey := aggregatable.Aggregate(signers[1:])
+	if aggKey == nil {
+		return ErrUnsupportedAggregate
+	}
+
+	precommit := &pbtypes.Vote{
+		Height:  height,
+		Round:   commit.Round(),
+		Type:    pbtypes.VoteType_Precommit,
+		BlockID: blockID,
+	}
+	signBytes := SignBytes(chainID, precommit)
+	if !aggKey.VerifyBytes(signBytes, NewDefaultSignature(commit.AggregatedSignature)) {
+		return fmt.Errorf("Invalid commit -- invalid aggregated signature")
+	}
+	return nil
+}
+
+// bitmapIsSet reports whether bit idx is set in bitmap, treating bitmap as
+// a little-endian sequence of bit-packed bytes.
+func bitmapIsSet(bitmap []byte, idx int) bool {
+	byteIdx := idx / 8
+	if byteIdx >= len(bitmap) {
+		return false
+	}
+	return bitmap[byteIdx]&(1<<uint(idx%8)) != 0
+}
+
 func (valSet *ValidatorSet) String() string {
 	return valSet.StringIndented("")
 }
@@ -348,7 +623,10 @@ func (ac accumComparable) Less(o interface{}) bool {
 // For testing
 
 // NOTE: PrivValidator are in order.
-func RandValidatorSet(logger *zap.Logger, numValidators int, votingPower def.INT) (*ValidatorSet, []*PrivValidator) {
+// The returned VoterSet is sampled from the ValidatorSet via SelectVoters,
+// seeded on the set's hash, so callers get a realistic (seed, voters) pair
+// without having to synthesize one by hand in every test.
+func RandValidatorSet(logger *zap.Logger, numValidators int, votingPower def.INT) (*ValidatorSet, *VoterSet, []*PrivValidator) {
 	vals := make([]*Validator, numValidators)
 	privValidators := make([]*PrivValidator, numValidators)
 	for i := 0; i < numValidators; i++ {
@@ -358,5 +636,6 @@ func RandValidatorSet(logger *zap.Logger, numValidators int, votingPower def.INT
 	}
 	valSet := NewValidatorSet(vals)
 	sort.Sort(PrivValidatorsByAddress(privValidators))
-	return valSet, privValidators
+	voterSet := valSet.SelectVoters(valSet.Hash(), numValidators)
+	return valSet, voterSet, privValidators
 }
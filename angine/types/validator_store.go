@@ -0,0 +1,185 @@
+// Copyright 2017 ZhongAn Information Technology Services Co.,Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/dappledger/AnnChain/module/lib/go-merkle"
+	"github.com/dappledger/AnnChain/module/xlib/def"
+)
+
+// removalTombstone is the sentinel value Remove writes to mark a pubkey as
+// unbonded as of a given height. It's distinguished from a real record by
+// being zero-length -- json.Marshal of a *Validator is never empty.
+var removalTombstone = []byte{}
+
+// ValidatorStore persists validators in an IAVL tree keyed by
+// blockbonded_height_bigendian || pubkey_bytes, as an alternative to
+// ValidatorSet's in-memory Validators slice. Keying by
+// bonded height means LoadAt can answer "what did the set look like at
+// height H" directly from the tree rather than replaying IncrementAccum
+// from genesis, and Hash() returns the tree's own root, which also gives
+// callers Merkle proofs of individual validator membership -- a primitive
+// ValidatorSet.Hash()'s SimpleHashFromHashables can't offer.
+type ValidatorStore struct {
+	tree *merkle.IAVLTree
+}
+
+// NewValidatorStore wraps an existing IAVL tree (e.g. one already opened
+// against a db by the caller) as a ValidatorStore.
+func NewValidatorStore(tree *merkle.IAVLTree) *ValidatorStore {
+	return &ValidatorStore{tree: tree}
+}
+
+// validatorStoreKey builds the blockbonded_height_bigendian || pubkey_bytes
+// key this store indexes by. A nil pubKeyBytes produces the smallest key
+// for the given height, useful as a range bound.
+func validatorStoreKey(bondedHeight def.INT, pubKeyBytes []byte) []byte {
+	key := make([]byte, 8+len(pubKeyBytes))
+	binary.BigEndian.PutUint64(key[:8], uint64(bondedHeight))
+	copy(key[8:], pubKeyBytes)
+	return key
+}
+
+// Add records val as bonded at bondedHeight. It reports false if a
+// validator already exists under that (height, pubkey) pair.
+func (vstore *ValidatorStore) Add(bondedHeight def.INT, val *Validator) (added bool) {
+	value, err := json.Marshal(val)
+	if err != nil {
+		return false
+	}
+	updated := vstore.tree.Set(validatorStoreKey(bondedHeight, val.PubKey.Bytes()), value)
+	return !updated
+}
+
+// Update overwrites the record for val at bondedHeight, reporting whether
+// one already existed there.
+func (vstore *ValidatorStore) Update(bondedHeight def.INT, val *Validator) (updated bool) {
+	value, err := json.Marshal(val)
+	if err != nil {
+		return false
+	}
+	return vstore.tree.Set(validatorStoreKey(bondedHeight, val.PubKey.Bytes()), value)
+}
+
+// Remove marks the validator under pubKeyBytes as unbonded as of
+// removalHeight. It writes a tombstone at removalHeight rather than
+// deleting the original Add/Update record at its bonding height: LoadAt
+// answers "what did the set look like at height H" by walking the tree up
+// to H and keeping the newest record per pubkey, so deleting the
+// bonding-height entry outright would make every LoadAt before
+// removalHeight forget the validator was ever bonded there.
+func (vstore *ValidatorStore) Remove(removalHeight def.INT, pubKeyBytes []byte) (val *Validator, removed bool) {
+	val, exists := vstore.mostRecentBefore(removalHeight, pubKeyBytes)
+	if !exists {
+		return nil, false
+	}
+	vstore.tree.Set(validatorStoreKey(removalHeight, pubKeyBytes), removalTombstone)
+	return val, true
+}
+
+// mostRecentBefore returns the newest non-tombstoned record for pubKeyBytes
+// at or before height, or (nil, false) if it's unbonded or was never
+// bonded by then.
+func (vstore *ValidatorStore) mostRecentBefore(height def.INT, pubKeyBytes []byte) (*Validator, bool) {
+	var latest *Validator
+	vstore.tree.IterateRange(nil, validatorStoreKey(height+1, nil), true, func(key, value []byte) bool {
+		if !bytes.Equal(key[8:], pubKeyBytes) {
+			return false
+		}
+		if len(value) == 0 {
+			latest = nil // tombstoned as of this height
+			return false
+		}
+		val := new(Validator)
+		if err := json.Unmarshal(value, val); err != nil {
+			return false
+		}
+		latest = val
+		return false
+	})
+	return latest, latest != nil
+}
+
+// GetByAddress looks up the validator bonded at bondedHeight under
+// pubKeyBytes directly via the tree, in O(log N).
+func (vstore *ValidatorStore) GetByAddress(bondedHeight def.INT, pubKeyBytes []byte) (val *Validator, exists bool) {
+	_, value, exists := vstore.tree.Get(validatorStoreKey(bondedHeight, pubKeyBytes))
+	if !exists {
+		return nil, false
+	}
+	val = new(Validator)
+	if err := json.Unmarshal(value, val); err != nil {
+		return nil, false
+	}
+	return val, true
+}
+
+func (vstore *ValidatorStore) HasAddress(bondedHeight def.INT, pubKeyBytes []byte) bool {
+	_, exists := vstore.GetByAddress(bondedHeight, pubKeyBytes)
+	return exists
+}
+
+// Hash returns the IAVL tree's own Merkle root.
+func (vstore *ValidatorStore) Hash() []byte {
+	return vstore.tree.Hash()
+}
+
+// LoadAt reconstructs the ValidatorSet bonded at or before height by
+// walking the tree up to that height's key range and keeping the most
+// recent record per pubkey, without replaying IncrementAccum from genesis.
+// A Remove tombstone is the most recent record exactly as often as a real
+// one is, so a pubkey removed at or before height is correctly left out of
+// the result even though its original bonding-height record is still in
+// the tree. The result is a lazily materialized, sorted view -- the same
+// shape ValidatorSet.Validators already is -- so every existing
+// ValidatorSet method (Iterate, Hash, Proposer, ...) keeps working
+// unchanged on it.
+//
+// The returned set is built via genValidatorSet rather than
+// NewValidatorSet, because NewValidatorSet calls IncrementAccum(1) on any
+// non-nil slice, which would stamp AnchorHeight as 1 regardless of
+// height. GetProposerAt and IncrementAccumTo both measure their jump as
+// targetHeight-AnchorHeight, so a wrong AnchorHeight here silently replays
+// the wrong number of rounds for every caller downstream.
+func (vstore *ValidatorStore) LoadAt(height def.INT) *ValidatorSet {
+	latest := map[string]*Validator{}
+	vstore.tree.IterateRange(nil, validatorStoreKey(height+1, nil), true, func(key, value []byte) bool {
+		pubKeyBytes := string(key[8:])
+		if len(value) == 0 {
+			delete(latest, pubKeyBytes) // tombstoned as of this height
+			return false
+		}
+		val := new(Validator)
+		if err := json.Unmarshal(value, val); err != nil {
+			return false
+		}
+		latest[pubKeyBytes] = val
+		return false
+	})
+
+	vals := make([]*Validator, 0, len(latest))
+	for _, val := range latest {
+		vals = append(vals, val)
+	}
+
+	valSet := genValidatorSet(vals)
+	valSet.AnchorHeight = height
+	valSet.LastProposer = valSet.Proposer()
+	return valSet
+}
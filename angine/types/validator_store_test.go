@@ -0,0 +1,111 @@
+// Copyright 2017 ZhongAn Information Technology Services Co.,Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/dappledger/AnnChain/module/lib/go-merkle"
+	"github.com/dappledger/AnnChain/module/xlib/def"
+)
+
+// newTestValidatorStore returns an empty, in-memory-backed ValidatorStore.
+func newTestValidatorStore() *ValidatorStore {
+	return NewValidatorStore(merkle.NewIAVLTree(0, nil))
+}
+
+func TestValidatorStoreAddGetByAddress(t *testing.T) {
+	store := newTestValidatorStore()
+	val, _ := RandValidator(zap.NewNop(), false, 10)
+
+	if !store.Add(100, val) {
+		t.Fatalf("Add at a fresh (height, pubkey) should report added=true")
+	}
+	if store.Add(100, val) {
+		t.Fatalf("Add at an already-occupied (height, pubkey) should report added=false")
+	}
+
+	got, exists := store.GetByAddress(100, val.PubKey.Bytes())
+	if !exists {
+		t.Fatalf("GetByAddress: expected the just-added validator to exist")
+	}
+	if !bytes.Equal(got.Address, val.Address) {
+		t.Fatalf("GetByAddress: got address %x, want %x", got.Address, val.Address)
+	}
+}
+
+// TestValidatorStoreRemoveLoadAt checks the tombstone semantics Remove
+// relies on: LoadAt before the removal height still sees the validator,
+// LoadAt at or after the removal height does not, and the original
+// bonding-height record is never deleted outright.
+func TestValidatorStoreRemoveLoadAt(t *testing.T) {
+	store := newTestValidatorStore()
+	val, _ := RandValidator(zap.NewNop(), false, 10)
+
+	const bondedHeight = 100
+	const removalHeight = 200
+	store.Add(bondedHeight, val)
+
+	removed, ok := store.Remove(removalHeight, val.PubKey.Bytes())
+	if !ok {
+		t.Fatalf("Remove: expected a bonded validator to be removable")
+	}
+	if !bytes.Equal(removed.Address, val.Address) {
+		t.Fatalf("Remove returned address %x, want %x", removed.Address, val.Address)
+	}
+
+	before := store.LoadAt(removalHeight - 1)
+	if !before.HasAddress(val.Address) {
+		t.Fatalf("LoadAt(removalHeight-1): validator should still be bonded")
+	}
+
+	at := store.LoadAt(removalHeight)
+	if at.HasAddress(val.Address) {
+		t.Fatalf("LoadAt(removalHeight): validator should be unbonded")
+	}
+
+	after := store.LoadAt(removalHeight + 1)
+	if after.HasAddress(val.Address) {
+		t.Fatalf("LoadAt(removalHeight+1): validator should still be unbonded")
+	}
+
+	// The bonding-height record itself must survive the tombstone, since
+	// history before removalHeight still needs it.
+	if _, exists := store.GetByAddress(bondedHeight, val.PubKey.Bytes()); !exists {
+		t.Fatalf("GetByAddress(bondedHeight): original record should not have been deleted")
+	}
+}
+
+// TestValidatorStoreLoadAtAnchorHeight checks that LoadAt stamps the
+// returned set's AnchorHeight to the queried height, not to the bonding
+// height of whatever validators happen to be in it -- GetProposerAt and
+// IncrementAccumTo both measure their jump as targetHeight-AnchorHeight, so
+// a wrong AnchorHeight here would silently replay the wrong number of
+// rounds for every caller downstream.
+func TestValidatorStoreLoadAtAnchorHeight(t *testing.T) {
+	store := newTestValidatorStore()
+	val, _ := RandValidator(zap.NewNop(), false, 10)
+	store.Add(100, val)
+
+	for _, height := range []def.INT{100, 250, 500} {
+		valSet := store.LoadAt(height)
+		if valSet.AnchorHeight != height {
+			t.Fatalf("LoadAt(%d): AnchorHeight=%d, want %d", height, valSet.AnchorHeight, height)
+		}
+	}
+}
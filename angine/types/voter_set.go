@@ -0,0 +1,273 @@
+// Copyright 2017 ZhongAn Information Technology Services Co.,Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	pbtypes "github.com/dappledger/AnnChain/angine/protos/types"
+	"github.com/dappledger/AnnChain/module/lib/go-merkle"
+	"github.com/dappledger/AnnChain/module/xlib/def"
+)
+
+// VoterSet is the signing committee for a given height: a fixed-size (or
+// smaller, if the validator set itself is small) subset of a ValidatorSet's
+// Validators, drawn via weighted random sampling without replacement from a
+// VRF seed. Anyone holding the seed (derived from the previous block hash)
+// and the full ValidatorSet can recompute a VoterSet and audit membership,
+// so the committee needs no extra on-chain bookkeeping beyond the seed.
+//
+// VoterSet mirrors the get/hash/verify surface of ValidatorSet so commits
+// can be checked against voters instead of the full validator set, while
+// Proposer() selection is untouched and still runs over all Validators.
+// NOTE: Not goroutine-safe.
+// NOTE: All get/set to voters should copy the value for safety.
+type VoterSet struct {
+	Voters []*Validator // NOTE: persisted via reflect, must be exported.
+
+	// cached (unexported)
+	totalVotingPower def.INT
+}
+
+// SelectVoters draws a maxVoters-sized committee from valSet using weighted
+// random sampling without replacement, seeded by seed (typically the
+// previous block hash). The same (seed, maxVoters) pair always yields the
+// same VoterSet, so the selection can be replayed and audited by anyone who
+// has the ValidatorSet and the seed.
+//
+// If maxVoters >= len(Validators), every validator becomes a voter and no
+// sampling happens. maxVoters <= 0 is the opposite request -- an explicitly
+// empty committee -- and returns a VoterSet with no voters rather than
+// silently falling back to the full set. Zero-power validators are never
+// picked. The VotingPower of each sampled voter is preserved verbatim from
+// the source validator, so VerifyCommit's +2/3 threshold is computed
+// against the sampled powers rather than some rescaled weight.
+func (valSet *ValidatorSet) SelectVoters(seed []byte, maxVoters int) *VoterSet {
+	if maxVoters <= 0 {
+		return &VoterSet{}
+	}
+	if maxVoters >= len(valSet.Validators) {
+		return voterSetFromValidators(valSet.Validators)
+	}
+
+	candidates := make([]*Validator, 0, len(valSet.Validators))
+	cumulative := make([]def.INT, 0, len(valSet.Validators))
+	var remainingPower def.INT
+	for _, val := range valSet.Validators {
+		if val.VotingPower <= 0 {
+			continue
+		}
+		candidates = append(candidates, val)
+		remainingPower += val.VotingPower
+		cumulative = append(cumulative, remainingPower)
+	}
+
+	stream := newVRFStream(seed)
+	voters := make([]*Validator, 0, maxVoters)
+	for len(voters) < maxVoters && len(candidates) > 0 {
+		r := def.INT(stream.next() % uint64(remainingPower))
+		idx := sort.Search(len(cumulative), func(i int) bool {
+			return cumulative[i] > r
+		})
+
+		picked := candidates[idx]
+		voters = append(voters, picked.Copy())
+
+		remainingPower -= picked.VotingPower
+		candidates = append(candidates[:idx], candidates[idx+1:]...)
+		cumulative = cumulative[:len(candidates)]
+		var running def.INT
+		for i, c := range candidates {
+			running += c.VotingPower
+			cumulative[i] = running
+		}
+	}
+
+	sort.Sort(ValidatorsByAddress(voters))
+	return &VoterSet{Voters: voters}
+}
+
+func voterSetFromValidators(vals []*Validator) *VoterSet {
+	voters := make([]*Validator, len(vals))
+	for i, val := range vals {
+		voters[i] = val.Copy()
+	}
+	sort.Sort(ValidatorsByAddress(voters))
+	return &VoterSet{Voters: voters}
+}
+
+// vrfStream turns a VRF seed into an infinite stream of pseudorandom
+// uint64s by repeatedly hashing the seed with an incrementing counter.
+// Swapping this for a real VRF (e.g. ECVRF keyed on the proposer) only
+// changes how `seed` is produced -- the sampling above just needs a stream
+// that looks uniform and reproduces from public data.
+type vrfStream struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func newVRFStream(seed []byte) *vrfStream {
+	return &vrfStream{seed: seed}
+}
+
+func (s *vrfStream) next() uint64 {
+	if len(s.buf) < 8 {
+		var ctr [8]byte
+		binary.BigEndian.PutUint64(ctr[:], s.counter)
+		s.counter++
+		h := sha256.Sum256(append(append([]byte{}, s.seed...), ctr[:]...))
+		s.buf = append(s.buf, h[:]...)
+	}
+	v := binary.BigEndian.Uint64(s.buf[:8])
+	s.buf = s.buf[8:]
+	return v
+}
+
+func VoterSetFromJsonBytes(data []byte) *VoterSet {
+	var voters []*Validator
+	if err := json.Unmarshal(data, &voters); err != nil {
+		fmt.Println("debug json unmarshal err", err)
+		return nil
+	}
+	return voterSetFromValidators(voters)
+}
+
+func (voterSet *VoterSet) JSONBytes() ([]byte, error) {
+	return json.Marshal(voterSet.Voters)
+}
+
+func (voterSet *VoterSet) Size() int {
+	return len(voterSet.Voters)
+}
+
+func (voterSet *VoterSet) TotalVotingPower() def.INT {
+	if voterSet.totalVotingPower == 0 {
+		for _, val := range voterSet.Voters {
+			voterSet.totalVotingPower += val.VotingPower
+		}
+	}
+	return voterSet.totalVotingPower
+}
+
+func (voterSet *VoterSet) HasAddress(address []byte) bool {
+	idx := sort.Search(len(voterSet.Voters), func(i int) bool {
+		return bytes.Compare(address, voterSet.Voters[i].Address) <= 0
+	})
+	return idx != len(voterSet.Voters) && bytes.Compare(voterSet.Voters[idx].Address, address) == 0
+}
+
+func (voterSet *VoterSet) GetByAddress(address []byte) (index int, val *Validator) {
+	idx := sort.Search(len(voterSet.Voters), func(i int) bool {
+		return bytes.Compare(address, voterSet.Voters[i].Address) <= 0
+	})
+	if idx != len(voterSet.Voters) && bytes.Compare(voterSet.Voters[idx].Address, address) == 0 {
+		return idx, voterSet.Voters[idx].Copy()
+	}
+	return 0, nil
+}
+
+func (voterSet *VoterSet) GetByIndex(index int) (address []byte, val *Validator) {
+	val = voterSet.Voters[index]
+	return val.Address, val.Copy()
+}
+
+func (voterSet *VoterSet) Hash() []byte {
+	if len(voterSet.Voters) == 0 {
+		return nil
+	}
+	hashables := make([]merkle.Hashable, len(voterSet.Voters))
+	for i, val := range voterSet.Voters {
+		hashables[i] = val
+	}
+	return merkle.SimpleHashFromHashables(hashables)
+}
+
+// VerifyCommit checks that +2/3 of the voter set's voting power signed
+// signBytes, the same rule ValidatorSet.VerifyCommit applies to the full
+// validator set -- but here commit.Precommits is indexed against the
+// sampled committee rather than every validator.
+func (voterSet *VoterSet) VerifyCommit(chainID string, blockID pbtypes.BlockID, height def.INT, commit *CommitCache) error {
+	if voterSet.Size() != len(commit.Precommits) {
+		return fmt.Errorf("Invalid commit -- wrong set size: %v vs %v", voterSet.Size(), len(commit.Precommits))
+	}
+	if height != commit.Height() {
+		return fmt.Errorf("Invalid commit -- wrong height: %v vs %v", height, commit.Height())
+	}
+
+	var talliedVotingPower def.INT
+	round := commit.Round()
+
+	for idx, precommit := range commit.Precommits {
+		// may be nil if voter skipped.
+		if !precommit.Exist() {
+			continue
+		}
+		pdata := precommit.GetData()
+		if pdata.Height != height {
+			return fmt.Errorf("Invalid commit -- wrong height: %v vs %v", height, pdata.Height)
+		}
+		if pdata.Round != round {
+			return fmt.Errorf("Invalid commit -- wrong round: %v vs %v", round, pdata.Round)
+		}
+		if pdata.Type != pbtypes.VoteType_Precommit {
+			return fmt.Errorf("Invalid commit -- not precommit @ index %v", idx)
+		}
+		_, val := voterSet.GetByIndex(idx)
+		// Validate signature
+		precommitSignBytes := SignBytes(chainID, pdata)
+		if !val.PubKey.VerifyBytes(precommitSignBytes, NewDefaultSignature(precommit.Signature)) {
+			return fmt.Errorf("Invalid commit -- invalid signature: %v", precommit)
+		}
+		if !blockID.Equals(pdata.BlockID) {
+			continue // Not an error, but doesn't count
+		}
+		// Good precommit!
+		talliedVotingPower += val.VotingPower
+	}
+
+	if talliedVotingPower > voterSet.TotalVotingPower()*2/3 {
+		return nil
+	}
+	return fmt.Errorf("Invalid commit -- insufficient voting power: got %v, needed %v",
+		talliedVotingPower, (voterSet.TotalVotingPower()*2/3 + 1))
+}
+
+func (voterSet *VoterSet) String() string {
+	return voterSet.StringIndented("")
+}
+
+func (voterSet *VoterSet) StringIndented(indent string) string {
+	if voterSet == nil {
+		return "nil-VoterSet"
+	}
+	voterStrings := []string{}
+	for _, val := range voterSet.Voters {
+		voterStrings = append(voterStrings, val.Copy().String())
+	}
+	return fmt.Sprintf(`VoterSet{
+%s  Voters:
+%s    %v
+%s}`,
+		indent,
+		indent, strings.Join(voterStrings, "\n"+indent+"    "),
+		indent)
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 ZhongAn Information Technology Services Co.,Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSelectVotersDeterministic(t *testing.T) {
+	valSet, _, _ := RandValidatorSet(zap.NewNop(), 10, 1)
+	seed := []byte("deterministic-seed")
+
+	first := valSet.SelectVoters(seed, 4)
+	second := valSet.SelectVoters(seed, 4)
+
+	if first.Size() != second.Size() {
+		t.Fatalf("got different committee sizes across runs: %d vs %d", first.Size(), second.Size())
+	}
+	for i := range first.Voters {
+		if !bytes.Equal(first.Voters[i].Address, second.Voters[i].Address) {
+			t.Fatalf("voter %d differs across runs with the same seed: %x vs %x",
+				i, first.Voters[i].Address, second.Voters[i].Address)
+		}
+	}
+}
+
+func TestSelectVotersZeroOrNegativeIsEmpty(t *testing.T) {
+	valSet, _, _ := RandValidatorSet(zap.NewNop(), 5, 1)
+
+	if got := valSet.SelectVoters([]byte("seed"), 0); got.Size() != 0 {
+		t.Fatalf("maxVoters=0 should yield an empty committee, got %d voters", got.Size())
+	}
+	if got := valSet.SelectVoters([]byte("seed"), -1); got.Size() != 0 {
+		t.Fatalf("negative maxVoters should yield an empty committee, got %d voters", got.Size())
+	}
+}
+
+func TestSelectVotersAllWhenMaxExceedsSize(t *testing.T) {
+	valSet, _, _ := RandValidatorSet(zap.NewNop(), 5, 1)
+
+	got := valSet.SelectVoters([]byte("seed"), 100)
+	if got.Size() != valSet.Size() {
+		t.Fatalf("expected every validator to be a voter when maxVoters exceeds the set, got %d of %d",
+			got.Size(), valSet.Size())
+	}
+}
+
+func TestVoterSetJSONRoundTrip(t *testing.T) {
+	valSet, _, _ := RandValidatorSet(zap.NewNop(), 5, 1)
+	voterSet := valSet.SelectVoters([]byte("seed"), 3)
+
+	data, err := voterSet.JSONBytes()
+	if err != nil {
+		t.Fatalf("JSONBytes: %v", err)
+	}
+	roundTripped := VoterSetFromJsonBytes(data)
+	if roundTripped == nil {
+		t.Fatalf("VoterSetFromJsonBytes returned nil")
+	}
+	if roundTripped.Size() != voterSet.Size() {
+		t.Fatalf("got %d voters after round-trip, want %d", roundTripped.Size(), voterSet.Size())
+	}
+	for i := range voterSet.Voters {
+		if !bytes.Equal(voterSet.Voters[i].Address, roundTripped.Voters[i].Address) {
+			t.Fatalf("voter %d address changed across JSON round-trip", i)
+		}
+	}
+}